@@ -0,0 +1,11 @@
+package main
+
+import "io"
+
+// WithCloser embeds io.Closer from another package, kept around so
+// ifacegen's own tests can exercise cross-package embedded interface
+// resolution in parseMethods.
+type WithCloser interface {
+	io.Closer
+	Name() string
+}
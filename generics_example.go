@@ -0,0 +1,9 @@
+package main
+
+// Container is a sample generic interface, kept around so ifacegen's own
+// tests can exercise type parameter support in parseMethods and the
+// code-gen templates.
+type Container[T any] interface {
+	Get(k string) (T, bool)
+	Put(k string, v T)
+}
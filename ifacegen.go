@@ -8,52 +8,133 @@
 //
 //    ifacegen -p myhttp -o httphandler_mock.go -i net/http.Handler
 //
+// Passing -m=controller generates a gomock-style mock instead, backed by
+// a mock.Controller and an EXPECT() recorder, e.g.
+//
+//    ifacegen -m=controller -o httphandler_mock.go -i net/http.Handler
+//
+// Passing -i all (or -all) generates a mock for every exported interface
+// in the package instead of just one, e.g.
+//
+//    ifacegen -m -outdir mocks -all -i net/http
+//
 package main
 
 import (
 	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
-	"go/build"
+	"go/ast"
 	"go/format"
 	"go/types"
-	"html/template"
 	"io/ioutil"
 	"log"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
+	"text/template"
 
-	"golang.org/x/tools/go/loader"
+	"golang.org/x/tools/go/packages"
 	"golang.org/x/tools/imports"
 )
 
 func main() {
-	receiver, output, srcPath, ifaceName, mock, mockInTest := parseFlag()
+	receiver, output, outdir, srcPath, ifaceName, mode, mockInTest, reflectMode, allIfaces := parseFlag()
+
+	thisPkg := newThisPackage(mockInTest)
 
-	iface := Interface{
-		Interface: ifaceName,
-		Receiver:  receiver,
+	ifaceNames := []string{ifaceName}
+	if allIfaces {
+		ifaceNames = discoverInterfaces(importPackage(srcPath))
+		if len(ifaceNames) == 0 {
+			log.Fatalf("no exported interfaces found in pkg:%s", srcPath)
+		}
 	}
 
-	thisPkg := newThisPackage(mockInTest)
-	if mock {
-		iface.PkgName = thisPkg.Name()
+	var ifaces []*Interface
+	for _, name := range ifaceNames {
+		iface := &Interface{Interface: name, Receiver: receiver}
+		if len(ifaceNames) > 1 {
+			// Force per-interface default receiver naming; a single -r
+			// can't apply to more than one generated struct.
+			iface.Receiver = ""
+		}
+		if mode != mockNone {
+			iface.PkgName = thisPkg.Name()
+		}
+		if reflectMode {
+			iface.Methods = reflectMethods(srcPath, name)
+		} else {
+			iface.Methods, iface.TypeParams, iface.TypeArgs = parseMethods(thisPkg, srcPath, name)
+		}
+		ifaces = append(ifaces, iface)
+	}
+
+	if len(ifaces) > 1 && mode == mockSimple {
+		// mockTpl's per-method call{{.Method}} const and {{.Method}}Call
+		// struct are keyed by method name alone, not by interface, so two
+		// interfaces sharing a method name collide at package scope
+		// whether they land in one -o file or separate -outdir files.
+		checkNoDuplicateMethodNames(ifaces)
+	}
+
+	if outdir != "" {
+		for _, iface := range ifaces {
+			fn := filepath.Join(outdir, outFileName(iface.Interface, mode))
+			writeCode(fn, genCode(iface, mode))
+		}
+		return
 	}
 
-	iface.Methods = parseMethods(thisPkg, srcPath, ifaceName)
+	if len(ifaces) == 1 {
+		writeCode(output, genCode(ifaces[0], mode))
+	} else {
+		writeCode(output, genAllCode(ifaces, mode))
+	}
+}
 
-	b := genCode(&iface)
-	writeCode(output, b)
+// discoverInterfaces returns the names of every exported interface type
+// declared at package scope in pkg, sorted for deterministic output. It
+// looks up names via pkg.Types.Scope(), the same way findInterface does
+// for a single name, rather than pkg.TypesInfo.Defs: Defs holds every
+// identifier definition in the type-checked syntax tree, including types
+// declared locally inside a function body, which aren't usable as
+// -i targets.
+func discoverInterfaces(pkg *packages.Package) []string {
+	scope := pkg.Types.Scope()
+	var names []string
+	for _, name := range scope.Names() {
+		if !ast.IsExported(name) {
+			continue
+		}
+		tn, ok := scope.Lookup(name).(*types.TypeName)
+		if !ok {
+			continue
+		}
+		named, ok := tn.Type().(*types.Named)
+		if !ok {
+			continue
+		}
+		if _, ok := named.Underlying().(*types.Interface); !ok {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
 func newThisPackage(mockInTest bool) *types.Package {
-	pkg := importPackage("", "")
+	pkg := importPackage("")
+	name := pkg.Types.Name()
 	if mockInTest {
-		pkg.Name += "_test"
+		name += "_test"
 	}
-	return types.NewPackage(pkg.ImportPath, pkg.Name)
+	return types.NewPackage(pkg.PkgPath, name)
 }
 
 func newPackageQualifier(thisPkg *types.Package) types.Qualifier {
@@ -65,20 +146,64 @@ func newPackageQualifier(thisPkg *types.Package) types.Qualifier {
 	}
 }
 
-func parseFlag() (receiver, output, srcPath, ifaceName string, mock, mockInTest bool) {
+// mockMode selects what kind of mock, if any, ifacegen generates for an
+// interface. It implements flag.Value so that both "-m" (the long
+// standing boolean-ish usage) and "-m=controller" work.
+type mockMode string
+
+const (
+	mockNone       mockMode = ""
+	mockSimple     mockMode = "mock"
+	mockController mockMode = "controller"
+)
+
+func (m *mockMode) String() string { return string(*m) }
+
+func (m *mockMode) Set(s string) error {
+	switch s {
+	case "true", "mock":
+		*m = mockSimple
+	case "controller":
+		*m = mockController
+	default:
+		return fmt.Errorf("unknown mock mode %q, want %q or %q", s, "mock", "controller")
+	}
+	return nil
+}
+
+// IsBoolFlag lets "-m" alone enable mockSimple, same as the old -m bool flag.
+func (m *mockMode) IsBoolFlag() bool { return true }
+
+func parseFlag() (receiver, output, outdir, srcPath, ifaceName string, mode mockMode, mockInTest, reflectMode, allIfaces bool) {
 	flag.StringVar(&receiver, "r", "", "Name of receiver, default to *{Interface}{Gen|Mock}")
 	flag.StringVar(&output, "o", "", "Name of output file, default to os.Stdout")
-	flag.StringVar(&ifaceName, "i", "", "Interface name, [{import_path}.]{Interface}, e.g. net/http.Handler, Foo. (Required)")
-	flag.BoolVar(&mock, "m", false, "Generate mock struct if true")
+	flag.StringVar(&outdir, "outdir", "", "Directory to write one file per interface into, instead of a single -o file; only valid with -i all/-all")
+	flag.StringVar(&ifaceName, "i", "", `Interface name, [{import_path}.]{Interface}, e.g. net/http.Handler, Foo. Pass "all" (or -all) to generate every exported interface in the package. (Required)`)
+	flag.Var(&mode, "m", `Generate mock struct if true, or a gomock-style controller mock if "controller"`)
 	flag.BoolVar(&mockInTest, "t", false, "Put the mock struct in test package if true")
+	flag.BoolVar(&reflectMode, "reflect", false, "Find the interface by reflection instead of parsing source, for interfaces ifacegen cannot type-check (e.g. third-party/stdlib packages with unexported types, build tags, or cgo)")
+	flag.BoolVar(&allIfaces, "all", false, `Generate every exported interface in the package, same as -i all`)
 	flag.Parse()
-	if ifaceName == "" {
+	if ifaceName == "" && !allIfaces {
 		fmt.Fprintln(os.Stderr, "interface name is required")
 		os.Exit(1)
 	}
 
-	i := strings.LastIndex(ifaceName, ".")
-	if i >= 0 {
+	if ifaceName == "all" {
+		allIfaces = true
+		ifaceName = ""
+	}
+	if allIfaces {
+		// The whole -i value, if any, names the package to scan, e.g.
+		// "-all -i github.com/foo/bar" or "-all -i net/http" — there's no
+		// trailing ".Interface" to split off, so skip that parse entirely
+		// (a domain-rooted import path has a '.' before its last '/' and
+		// would otherwise look malformed).
+		if ifaceName != "" {
+			srcPath = ifaceName
+			ifaceName = ""
+		}
+	} else if i := strings.LastIndex(ifaceName, "."); i >= 0 {
 		j := strings.LastIndex(ifaceName, "/")
 		if j >= i {
 			log.Fatalf("malformed ifacename:%q, '.' before '/'", ifaceName)
@@ -86,37 +211,115 @@ func parseFlag() (receiver, output, srcPath, ifaceName string, mock, mockInTest
 		srcPath = ifaceName[:i]
 		ifaceName = ifaceName[i+1:]
 	}
-	return receiver, output, srcPath, ifaceName, mock, mockInTest
+	if allIfaces && reflectMode {
+		log.Fatalf("-all is not supported together with -reflect")
+	}
+	if outdir != "" && output != "" {
+		log.Fatalf("-o and -outdir are mutually exclusive")
+	}
+	if outdir != "" && !allIfaces {
+		log.Fatalf("-outdir is only valid with -i all/-all")
+	}
+	if reflectMode && srcPath == "" {
+		log.Fatalf("-reflect requires an import path in -i, e.g. -i encoding/json.Marshaler")
+	}
+	return receiver, output, outdir, srcPath, ifaceName, mode, mockInTest, reflectMode, allIfaces
 }
 
-func genCode(iface *Interface) []byte {
-	var tpl *template.Template
-	var sfx string
-	if iface.PkgName != "" {
-		tpl = mockTpl
-		sfx = "Mock"
-	} else {
-		tpl = genTpl
-		sfx = "Gen"
+// tplForMode returns the template for mode and the struct-name suffix it
+// uses when the caller doesn't supply a receiver, e.g. "Mock" for
+// FooMock.
+func tplForMode(mode mockMode) (tpl *template.Template, sfx string) {
+	switch mode {
+	case mockController:
+		return controllerTpl, "Mock"
+	case mockSimple:
+		return mockTpl, "Mock"
+	default:
+		return genTpl, "Gen"
 	}
+}
+
+// renderIface executes the template for mode against iface, returning the
+// raw, unformatted generated source.
+func renderIface(iface *Interface, mode mockMode) []byte {
+	tpl, sfx := tplForMode(mode)
+	var data interface{} = iface
 
 	if iface.Receiver == "" {
 		iface.Receiver = "*" + iface.Interface + sfx
 	}
 	iface.Struct = strings.TrimPrefix(iface.Receiver, "*")
 
+	if mode == mockController {
+		data = newCtrlInterface(iface)
+	}
+
 	var buf bytes.Buffer
-	err := tpl.Execute(&buf, iface)
+	err := tpl.Execute(&buf, data)
 	fatalOnErr(err, "execute template:%s", tpl.Name())
-	gen := buf.Bytes()
+	return buf.Bytes()
+}
 
-	if iface.PkgName == "" {
-		gen, err = format.Source(gen)
+// formatGenerated runs goimports over gen if it has a package clause
+// (mock modes), or just gofmt if it's a bare skeleton meant to be pasted
+// into an existing file (the default, non-mock mode).
+func formatGenerated(gen []byte, hasPkg bool) []byte {
+	var out []byte
+	var err error
+	if hasPkg {
+		out, err = imports.Process("", gen, nil)
 	} else {
-		gen, err = imports.Process("", gen, nil)
+		out, err = format.Source(gen)
+	}
+	fatalOnErr(err, "format/imports of code\n`%s`", gen)
+	return out
+}
+
+func genCode(iface *Interface, mode mockMode) []byte {
+	return formatGenerated(renderIface(iface, mode), iface.PkgName != "")
+}
+
+// genAllCode renders every interface in ifaces and formats them together
+// as a single file, deduplicating the repeated package clause that each
+// rendered chunk carries, along with controllerTpl's import of the mock
+// package.
+func genAllCode(ifaces []*Interface, mode mockMode) []byte {
+	var chunks [][]byte
+	for i, iface := range ifaces {
+		gen := renderIface(iface, mode)
+		if i > 0 && iface.PkgName != "" {
+			gen = bytes.Replace(gen, []byte("package "+iface.PkgName+"\n"), nil, 1)
+			gen = bytes.Replace(gen, []byte("import mock \""+mockPkgImportPath+"\"\n"), nil, 1)
+		}
+		chunks = append(chunks, gen)
+	}
+	hasPkg := len(ifaces) > 0 && ifaces[0].PkgName != ""
+	return formatGenerated(bytes.Join(chunks, []byte("\n")), hasPkg)
+}
+
+// checkNoDuplicateMethodNames fails fast if two interfaces share a method
+// name. mockTpl's per-method "call{{.Method}}" const and "{{.Method}}Call"
+// struct are keyed by method name alone, not by interface, so combining
+// such interfaces with -all (into one -o file, or separate files in the
+// same -outdir package) would redeclare both at package scope.
+func checkNoDuplicateMethodNames(ifaces []*Interface) {
+	owner := map[string]string{}
+	for _, iface := range ifaces {
+		for _, m := range iface.Methods {
+			if other, ok := owner[m.Method]; ok {
+				log.Fatalf("-all: %s.%s and %s.%s would both generate call%s/%sCall in the same package; rename one of the methods, or generate these interfaces separately", other, m.Method, iface.Interface, m.Method, m.Method, m.Method)
+			}
+			owner[m.Method] = iface.Interface
+		}
 	}
-	fatalOnErr(err, "format/imports of code\n`%s`", buf.Bytes())
-	return gen
+}
+
+// outFileName derives a default -outdir file name for an interface, e.g.
+// "FooMock.go".
+func outFileName(ifaceName string, mode mockMode) string {
+	_, sfx := tplForMode(mode)
+	return ifaceName + sfx + ".go"
 }
 
 func writeCode(fn string, b []byte) {
@@ -129,13 +332,22 @@ func writeCode(fn string, b []byte) {
 	}
 }
 
+// Field is a single named, typed parameter, used by mockTpl to build a
+// per-method FooCall struct that records the arguments of each call.
+type Field struct {
+	Name string
+	Type string
+}
+
 type Method struct {
-	Method     string
-	Sig        string
-	Params     string
-	Args       string
-	Results    string
-	ResultVars string
+	Method       string
+	Sig          string
+	Params       string
+	Args         string
+	Results      string
+	ResultVars   string
+	ParamFields  []Field
+	ResultFields []Field
 }
 
 type Interface struct {
@@ -144,76 +356,91 @@ type Interface struct {
 	Struct    string
 	Receiver  string
 	Methods   []*Method
+
+	// TypeParams is "[T any, U comparable]", or "" if the interface isn't
+	// generic. TypeArgs is the matching bare-name instantiation, "[T, U]".
+	TypeParams string
+	TypeArgs   string
 }
 
-func importPackage(vendorPrefix, srcPath string) *build.Package {
-	// package is e.g. "net/http" if it is in GOROOT or GOPATH,
-	// package is e.g. "github.com/foo/bar/vendor/golang.org/x/tools/imports" if it is a vendor package.
-	if srcPath == "" {
-		wd, err := os.Getwd()
-		fatalOnErr(err, "getwd")
-		pkg, err := build.ImportDir(wd, 0)
-		fatalOnErr(err, "importdir wd:%q", wd)
-		return pkg
+// loadMode is what parseMethods needs from go/packages: full type
+// information plus the import graph, so that an interface embedding
+// another interface from a different package resolves correctly.
+const loadMode = packages.NeedName | packages.NeedTypes | packages.NeedTypesInfo |
+	packages.NeedSyntax | packages.NeedDeps | packages.NeedImports
+
+// importPackage loads the package at srcPath, or the package in the
+// current directory if srcPath is empty, e.g. "net/http" or
+// "github.com/foo/bar/baz".
+func importPackage(srcPath string) *packages.Package {
+	pattern := srcPath
+	if pattern == "" {
+		pattern = "."
 	}
-
-	for {
-		p := srcPath
-		if vendorPrefix != "" && vendorPrefix != "." {
-			p = filepath.Join(vendorPrefix, "vendor", srcPath)
-		}
-		pkg, err := build.Import(p, "", 0)
-		if err == nil {
-			return pkg
-		}
-		if vendorPrefix == "" || vendorPrefix == "." {
-			fatalOnErr(err, "import pkg:%s", srcPath)
-		}
-		vendorPrefix = filepath.Dir(vendorPrefix)
+	pkgs, err := packages.Load(&packages.Config{Mode: loadMode}, pattern)
+	fatalOnErr(err, "load pkg:%s", pattern)
+	if n := packages.PrintErrors(pkgs); n > 0 {
+		log.Fatalf("%d error(s) loading pkg:%s", n, pattern)
 	}
-}
-
-func parseMethods(thisPkg *types.Package, srcPath, ifaceName string) []*Method {
-	pkg := importPackage(thisPkg.Path(), srcPath)
-
-	var srcFiles []string
-	for _, fn := range pkg.GoFiles {
-		srcFiles = append(srcFiles, filepath.Join(pkg.Dir, fn))
+	if len(pkgs) != 1 {
+		log.Fatalf("pkg:%s resolved to %d packages, want 1", pattern, len(pkgs))
 	}
-	info := parseTypeInfo(srcFiles)
-	iface := findInterface(info, ifaceName)
+	return pkgs[0]
+}
 
-	var methods []*Method
-	for i := 0; i < iface.NumMethods(); i++ {
-		methods = append(methods, parseMethod(thisPkg, iface.Method(i)))
+// parseMethods returns ifaceName's methods, plus its type parameter list
+// formatted as "[T any, U comparable]" and the matching bare-name
+// instantiation "[T, U]" (both "" if the interface isn't generic).
+func parseMethods(thisPkg *types.Package, srcPath, ifaceName string) (methods []*Method, typeParams, typeArgs string) {
+	pkg := importPackage(srcPath)
+	iface, tparams := findInterface(pkg, ifaceName)
+	typeParams, typeArgs = formatTypeParams(thisPkg, tparams)
+
+	// types.NewMethodSet, like iface.NumMethods()/Method(i), includes
+	// methods promoted through embedded interfaces, including ones
+	// embedded from other packages.
+	ms := types.NewMethodSet(iface)
+	for i := 0; i < ms.Len(); i++ {
+		methods = append(methods, parseMethod(thisPkg, ms.At(i).Obj().(*types.Func)))
 	}
-	return methods
+	return methods, typeParams, typeArgs
 }
 
-func parseTypeInfo(srcFiles []string) *types.Info {
-	var conf loader.Config
-	conf.CreateFromFilenames("", srcFiles...)
-	conf.AllowErrors = true
-	conf.TypeChecker.Error = func(error) {}
-	conf.TypeChecker.DisableUnusedImportCheck = true
-	conf.TypeCheckFuncBodies = func(path string) bool { return false }
-
-	prog, err := conf.Load()
-	fatalOnErr(err, "load")
-	return &prog.Created[0].Info
+func findInterface(pkg *packages.Package, name string) (*types.Interface, *types.TypeParamList) {
+	obj := pkg.Types.Scope().Lookup(name)
+	if obj == nil {
+		log.Fatalf("interface:%s is not found in pkg:%s", name, pkg.PkgPath)
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok {
+		log.Fatalf("%s in pkg:%s is not a named type", name, pkg.PkgPath)
+	}
+	iface, ok := named.Underlying().(*types.Interface)
+	if !ok {
+		log.Fatalf("%s in pkg:%s is not an interface", name, pkg.PkgPath)
+	}
+	return iface, named.TypeParams()
 }
 
-func findInterface(info *types.Info, name string) *types.Interface {
-	for k, o := range info.Defs {
-		if k.Name == name {
-			iface, ok := o.Type().Underlying().(*types.Interface)
-			if ok {
-				return iface
-			}
+// formatTypeParams renders a *types.TypeParamList as both its
+// declaration, e.g. "[T any, U comparable]", and its bare-name
+// instantiation, e.g. "[T, U]". Both are "" if tparams is empty.
+func formatTypeParams(thisPkg *types.Package, tparams *types.TypeParamList) (params, args string) {
+	if tparams.Len() == 0 {
+		return "", ""
+	}
+	pkgQual := newPackageQualifier(thisPkg)
+	var decl, names string
+	for i := 0; i < tparams.Len(); i++ {
+		tp := tparams.At(i)
+		if i != 0 {
+			decl += ", "
+			names += ", "
 		}
+		decl += tp.Obj().Name() + " " + types.TypeString(tp.Constraint(), pkgQual)
+		names += tp.Obj().Name()
 	}
-	log.Fatalf("interface:%s is not found", name)
-	return nil
+	return "[" + decl + "]", "[" + names + "]"
 }
 
 func parseTuple(thisPkg *types.Package, tuple *types.Tuple, namePrefix string) (params, args string) {
@@ -242,6 +469,32 @@ func parseTuple(thisPkg *types.Package, tuple *types.Tuple, namePrefix string) (
 	return params, args
 }
 
+// parseTupleFields is parseTuple's structured counterpart: it returns
+// each parameter's name and type instead of flattening them into a
+// "name type, name type" string, so templates can build per-field code
+// such as a FooCall struct.
+func parseTupleFields(thisPkg *types.Package, tuple *types.Tuple, namePrefix string) []Field {
+	pkgQual := newPackageQualifier(thisPkg)
+	var errNameUsed bool
+	var fields []Field
+	for i := 0; i < tuple.Len(); i++ {
+		v := tuple.At(i)
+		name := v.Name()
+		ty := types.TypeString(v.Type(), pkgQual)
+
+		if name == "" && namePrefix != "" {
+			if ty == "error" && !errNameUsed {
+				errNameUsed = true
+				name = "err"
+			} else {
+				name = namePrefix + strconv.Itoa(i)
+			}
+		}
+		fields = append(fields, Field{Name: name, Type: ty})
+	}
+	return fields
+}
+
 func parseMethod(thisPkg *types.Package, fn *types.Func) *Method {
 	pkgQual := newPackageQualifier(thisPkg)
 	sig := fn.Type().(*types.Signature)
@@ -250,15 +503,148 @@ func parseMethod(thisPkg *types.Package, fn *types.Func) *Method {
 	results, resvar := parseTuple(thisPkg, sig.Results(), "r")
 
 	return &Method{
-		Method:     fn.Name(),
-		Sig:        types.TypeString(sig, pkgQual),
-		Params:     params,
-		Args:       args,
-		Results:    results,
-		ResultVars: resvar,
+		Method:       fn.Name(),
+		Sig:          types.TypeString(sig, pkgQual),
+		Params:       params,
+		Args:         args,
+		Results:      results,
+		ResultVars:   resvar,
+		ParamFields:  parseTupleFields(thisPkg, sig.Params(), "a"),
+		ResultFields: parseTupleFields(thisPkg, sig.Results(), "r"),
+	}
+}
+
+// reflectMethod is the JSON shape emitted by reflectProgTpl: a method's
+// name plus its parameter/result types rendered by reflect.Type.String(),
+// which (like types.TypeString) renders them package-qualified, e.g.
+// "*bytes.Buffer" or "context.Context".
+type reflectMethod struct {
+	Name     string
+	Params   []string
+	Results  []string
+	Variadic bool
+}
+
+// reflectMethods finds ifaceName's methods by compiling and running a
+// small program that imports srcPath and reflects over the interface,
+// rather than type-checking source with parseMethods. This works for
+// interfaces parseMethods can't handle: unexported types, build-tag
+// gated files, cgo, anything outside the current module.
+func reflectMethods(srcPath, ifaceName string) []*Method {
+	dir, err := ioutil.TempDir("", "ifacegen-reflect")
+	fatalOnErr(err, "create tempdir")
+	defer os.RemoveAll(dir)
+
+	progFile := filepath.Join(dir, "main.go")
+	f, err := os.Create(progFile)
+	fatalOnErr(err, "create reflect program:%s", progFile)
+	data := struct {
+		ImportPath string
+		Interface  string
+	}{srcPath, ifaceName}
+	err = reflectProgTpl.Execute(f, data)
+	fatalOnErr(err, "execute reflect program template")
+	fatalOnErr(f.Close(), "close reflect program:%s", progFile)
+
+	out, err := exec.Command("go", "run", progFile).Output()
+	if ee, ok := err.(*exec.ExitError); ok {
+		log.Fatalf("run reflect program:%s\n%s", err, ee.Stderr)
+	}
+	fatalOnErr(err, "run reflect program:%s", progFile)
+
+	var reflected []reflectMethod
+	fatalOnErr(json.Unmarshal(out, &reflected), "unmarshal reflect program output")
+
+	var methods []*Method
+	for _, rm := range reflected {
+		methods = append(methods, newReflectMethod(rm))
+	}
+	return methods
+}
+
+func newReflectMethod(rm reflectMethod) *Method {
+	params, args, paramFields := tupleFromTypes(rm.Params, "a", rm.Variadic)
+	results, resultVars, resultFields := tupleFromTypes(rm.Results, "r", false)
+	return &Method{
+		Method:       rm.Name,
+		Sig:          "func(" + params + ") (" + results + ")",
+		Params:       params,
+		Args:         args,
+		Results:      results,
+		ResultVars:   resultVars,
+		ParamFields:  paramFields,
+		ResultFields: resultFields,
 	}
 }
 
+// tupleFromTypes is parseTuple/parseTupleFields's counterpart for types
+// discovered via reflection instead of go/types: it builds a
+// "name type, name type" tuple with auto-generated names from a plain
+// list of rendered type strings.
+func tupleFromTypes(tys []string, namePrefix string, variadic bool) (params, args string, fields []Field) {
+	var errNameUsed bool
+	for i, ty := range tys {
+		name := namePrefix + strconv.Itoa(i)
+		if ty == "error" && !errNameUsed {
+			errNameUsed = true
+			name = "err"
+		}
+		if variadic && i == len(tys)-1 {
+			ty = "..." + strings.TrimPrefix(ty, "[]")
+		}
+		if i != 0 {
+			params += ", "
+			args += ", "
+		}
+		params += name + " " + ty
+		args += name
+		fields = append(fields, Field{Name: name, Type: ty})
+	}
+	return params, args, fields
+}
+
+var reflectProgTpl = template.Must(template.New("reflectProg").Parse(`package main
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+
+	pkg_ {{printf "%q" .ImportPath}}
+)
+
+func main() {
+	ty := reflect.TypeOf((*pkg_.{{.Interface}})(nil)).Elem()
+
+	type method struct {
+		Name     string
+		Params   []string
+		Results  []string
+		Variadic bool
+	}
+	var methods []method
+	for i := 0; i < ty.NumMethod(); i++ {
+		m := ty.Method(i)
+		var params, results []string
+		for j := 0; j < m.Type.NumIn(); j++ {
+			params = append(params, m.Type.In(j).String())
+		}
+		for j := 0; j < m.Type.NumOut(); j++ {
+			results = append(results, m.Type.Out(j).String())
+		}
+		methods = append(methods, method{
+			Name:     m.Name,
+			Params:   params,
+			Results:  results,
+			Variadic: m.Type.IsVariadic(),
+		})
+	}
+	if err := json.NewEncoder(os.Stdout).Encode(methods); err != nil {
+		panic(err)
+	}
+}
+`))
+
 func fatalOnErr(err error, format string, args ...interface{}) {
 	if err != nil {
 		log.Fatalf(format+" err:"+err.Error(), args...)
@@ -266,13 +652,31 @@ func fatalOnErr(err error, format string, args ...interface{}) {
 }
 
 var genTpl = template.Must(template.New("gen").Parse(`{{with $x := .}}{{range .Methods}}
-func (m {{$x.Receiver}}) {{.Method}}({{.Params}}) ({{.Results}}) {
+func (m {{$x.Receiver}}{{$x.TypeArgs}}) {{.Method}}({{.Params}}) ({{.Results}}) {
 }
 {{end}}
 {{end}}
 `))
 
-var mockTpl = template.Must(template.New("mock").Parse(`// @generated by ifacegen
+// tplFuncs are helpers shared by the mock templates for turning a
+// parameter name like "a0" into an exported FooCall field name "A0", or
+// a method name like "Foo" into an unexported slice field "fooCalls".
+var tplFuncs = template.FuncMap{
+	"export": func(s string) string {
+		if s == "" {
+			return s
+		}
+		return strings.ToUpper(s[:1]) + s[1:]
+	},
+	"unexport": func(s string) string {
+		if s == "" {
+			return s
+		}
+		return strings.ToLower(s[:1]) + s[1:]
+	},
+}
+
+var mockTpl = template.Must(template.New("mock").Funcs(tplFuncs).Parse(`// @generated by ifacegen
 {{with $x := .}}
 package {{$x.PkgName}}
 
@@ -280,18 +684,30 @@ const (
   {{range $i, $m := $x.Methods}}call{{$m.Method}} = {{$i}}
   {{end}}
 )
-
-type {{$x.Struct}} struct {
+{{range $x.Methods}}
+type {{.Method}}Call{{$x.TypeParams}} struct {
+  {{range .ParamFields}}{{export .Name}} {{.Type}}
+  {{end}}
+}
+{{end}}
+type {{$x.Struct}}{{$x.TypeParams}} struct {
   PanicIfNotMocked bool
 
   {{range $x.Methods}}
   {{.Method}}Mock {{.Sig}}{{end}}
 
   callCounts [{{len $.Methods}}]int32
+
+  mu sync.RWMutex
+  {{range $x.Methods}}{{unexport .Method}}Calls []{{.Method}}Call{{$x.TypeArgs}}
+  {{end}}
 }
 {{range $x.Methods}}
-func (m {{$x.Receiver}}) {{.Method}}({{.Params}}) ({{.Results}}) {
+func (m {{$x.Receiver}}{{$x.TypeArgs}}) {{.Method}}({{.Params}}) ({{.Results}}) {
   atomic.AddInt32(&m.callCounts[call{{.Method}}], 1)
+  m.mu.Lock()
+  m.{{unexport .Method}}Calls = append(m.{{unexport .Method}}Calls, {{.Method}}Call{{$x.TypeArgs}}{ {{range .ParamFields}}{{export .Name}}: {{.Name}}, {{end}} })
+  m.mu.Unlock()
   if m.{{.Method}}Mock == nil {
     if m.PanicIfNotMocked {
       panic("{{.Method}} is not mocked")
@@ -301,9 +717,106 @@ func (m {{$x.Receiver}}) {{.Method}}({{.Params}}) ({{.Results}}) {
   {{if .Results}}return {{end}}m.{{.Method}}Mock({{.Args}})
 }
 
-func (m {{$x.Receiver}}) {{.Method}}CallCount() int {
+func (m {{$x.Receiver}}{{$x.TypeArgs}}) {{.Method}}CallCount() int {
   return int(atomic.LoadInt32(&m.callCounts[call{{.Method}}]))
 }
+
+func (m {{$x.Receiver}}{{$x.TypeArgs}}) {{.Method}}Calls() []{{.Method}}Call{{$x.TypeArgs}} {
+  m.mu.RLock()
+  defer m.mu.RUnlock()
+  calls := make([]{{.Method}}Call{{$x.TypeArgs}}, len(m.{{unexport .Method}}Calls))
+  copy(calls, m.{{unexport .Method}}Calls)
+  return calls
+}
+{{end}}
+
+// Reset clears all recorded calls and call counts.
+func (m {{$x.Receiver}}{{$x.TypeArgs}}) Reset() {
+  m.mu.Lock()
+  defer m.mu.Unlock()
+  {{range $x.Methods}}m.{{unexport .Method}}Calls = nil
+  {{end}}
+  for i := range m.callCounts {
+    atomic.StoreInt32(&m.callCounts[i], 0)
+  }
+}
+{{end}}
+`))
+
+// ctrlMethod adds the data controllerTpl needs on top of Method: the
+// plain argument names to pass into mock.Controller and the typed
+// result fields to cast its return values back into.
+type ctrlMethod struct {
+	*Method
+	ArgNames []string
+	Rets     []Field
+}
+
+// ctrlInterface is the template data for controllerTpl.
+type ctrlInterface struct {
+	*Interface
+	Methods []*ctrlMethod
+}
+
+func newCtrlInterface(iface *Interface) *ctrlInterface {
+	ci := &ctrlInterface{Interface: iface}
+	for _, m := range iface.Methods {
+		ci.Methods = append(ci.Methods, &ctrlMethod{
+			Method:   m,
+			ArgNames: splitArgNames(m.Args),
+			Rets:     m.ResultFields,
+		})
+	}
+	return ci
+}
+
+func splitArgNames(args string) []string {
+	if args == "" {
+		return nil
+	}
+	return strings.Split(args, ", ")
+}
+
+// mockPkgImportPath is the vendored runtime package controllerTpl's
+// output depends on. It's hardcoded into the generated import rather
+// than left for imports.Process to infer from the "mock" identifier:
+// "mock" is common enough that a consumer's own build graph may already
+// have another package by that name, which goimports would otherwise
+// silently prefer.
+const mockPkgImportPath = "github.com/zncoder/ifacegen/mock"
+
+var controllerTpl = template.Must(template.New("controller").Parse(`// @generated by ifacegen
+{{with $x := .}}
+package {{$x.PkgName}}
+
+import mock "` + mockPkgImportPath + `"
+
+type {{$x.Struct}}{{$x.TypeParams}} struct {
+  ctrl *mock.Controller
+}
+
+func New{{$x.Struct}}{{$x.TypeParams}}(ctrl *mock.Controller) {{$x.Receiver}}{{$x.TypeArgs}} {
+  return &{{$x.Struct}}{{$x.TypeArgs}}{ctrl: ctrl}
+}
+
+func (m {{$x.Receiver}}{{$x.TypeArgs}}) EXPECT() *{{$x.Struct}}Recorder{{$x.TypeArgs}} {
+  return &{{$x.Struct}}Recorder{{$x.TypeArgs}}{mock: m}
+}
+
+type {{$x.Struct}}Recorder{{$x.TypeParams}} struct {
+  mock {{$x.Receiver}}{{$x.TypeArgs}}
+}
+{{range $x.Methods}}
+func (m {{$x.Receiver}}{{$x.TypeArgs}}) {{.Method.Method}}({{.Params}}) ({{.Results}}) {
+  {{if .Rets}}ret := m.ctrl.Call(m, {{printf "%q" .Method.Method}}{{range .ArgNames}}, {{.}}{{end}})
+  {{range $i, $r := .Rets}}{{$r.Name}}, _ = ret[{{$i}}].({{$r.Type}})
+  {{end}}return {{.ResultVars}}{{else}}m.ctrl.Call(m, {{printf "%q" .Method.Method}}{{range .ArgNames}}, {{.}}{{end}})
+  return{{end}}
+}
+
+func (mr *{{$x.Struct}}Recorder{{$x.TypeArgs}}) {{.Method.Method}}({{range $i, $a := .ArgNames}}{{if $i}}, {{end}}{{$a}} interface{}{{end}}) *mock.Call {
+  return mr.mock.ctrl.RecordCall(mr.mock, {{printf "%q" .Method.Method}}{{range .ArgNames}}, {{.}}{{end}})
+}
 {{end}}
 {{end}}
 `))
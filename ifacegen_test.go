@@ -0,0 +1,188 @@
+package main
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseMethodsGenerics(t *testing.T) {
+	thisPkg := newThisPackage(false)
+	methods, typeParams, typeArgs := parseMethods(thisPkg, "", "Container")
+	if typeParams != "[T any]" {
+		t.Errorf("typeParams = %q, want %q", typeParams, "[T any]")
+	}
+	if typeArgs != "[T]" {
+		t.Errorf("typeArgs = %q, want %q", typeArgs, "[T]")
+	}
+
+	want := map[string]string{
+		"Get": "k string",
+		"Put": "k string, v T",
+	}
+	if len(methods) != len(want) {
+		t.Fatalf("got %d methods, want %d", len(methods), len(want))
+	}
+	for _, m := range methods {
+		if params, ok := want[m.Method]; !ok {
+			t.Errorf("unexpected method %q", m.Method)
+		} else if m.Params != params {
+			t.Errorf("%s.Params = %q, want %q", m.Method, m.Params, params)
+		}
+	}
+}
+
+// TestParseMethodsEmbeddedCrossPackage exercises the go/packages-based
+// resolution chunk0-3 introduced: WithCloser embeds io.Closer from a
+// different package, and parseMethods must flatten that promoted method
+// into the method set alongside WithCloser's own Name.
+func TestParseMethodsEmbeddedCrossPackage(t *testing.T) {
+	thisPkg := newThisPackage(false)
+	methods, _, _ := parseMethods(thisPkg, "", "WithCloser")
+
+	want := map[string]string{
+		"Close": "error",
+		"Name":  "string",
+	}
+	if len(methods) != len(want) {
+		t.Fatalf("got %d methods, want %d: %v", len(methods), len(want), methods)
+	}
+	for _, m := range methods {
+		results, ok := want[m.Method]
+		if !ok {
+			t.Errorf("unexpected method %q", m.Method)
+			continue
+		}
+		if !strings.HasSuffix(m.Results, results) {
+			t.Errorf("%s.Results = %q, want suffix %q", m.Method, m.Results, results)
+		}
+	}
+}
+
+func TestGenCodeGenerics(t *testing.T) {
+	for _, mode := range []mockMode{mockNone, mockSimple, mockController} {
+		iface := Interface{Interface: "Container", Receiver: "*ContainerMock"}
+		thisPkg := newThisPackage(false)
+		if mode != mockNone {
+			iface.PkgName = thisPkg.Name()
+		}
+		iface.Methods, iface.TypeParams, iface.TypeArgs = parseMethods(thisPkg, "", "Container")
+
+		b := genCode(&iface, mode)
+		if !strings.Contains(string(b), "[T") {
+			t.Errorf("mode %q: generated code has no type parameter:\n%s", mode, b)
+		}
+	}
+}
+
+// TestControllerCodeIsValidGo parses controllerTpl's output as Go source.
+// controllerTpl quotes method names with {{printf "%q" ...}}; if the
+// template were ever an html/template instead of text/template, those
+// quotes get HTML-escaped into "&#34;", which is a syntax error. This
+// guards against that regression class.
+func TestControllerCodeIsValidGo(t *testing.T) {
+	thisPkg := newThisPackage(false)
+	iface := Interface{Interface: "Container", Receiver: "*ContainerMock", PkgName: thisPkg.Name()}
+	iface.Methods, iface.TypeParams, iface.TypeArgs = parseMethods(thisPkg, "", "Container")
+
+	b := genCode(&iface, mockController)
+	if _, err := parser.ParseFile(token.NewFileSet(), "controller_mock.go", b, 0); err != nil {
+		t.Fatalf("generated controller mock is not valid Go: %v\n%s", err, b)
+	}
+}
+
+// TestDefaultMockRecordsCallsAndReset generates a -m mock for Adder,
+// compiles it alongside a small driver program, and runs it to confirm
+// the mock records call arguments and that Reset clears them — the
+// chunk0-2 behavior, which genCode's other tests only check via
+// substrings, not by actually running the generated code. This follows
+// the same "compile and run a generated program" approach reflectMethods
+// already uses for -reflect mode.
+func TestDefaultMockRecordsCallsAndReset(t *testing.T) {
+	thisPkg := newThisPackage(false)
+	iface := Interface{Interface: "Adder", Receiver: "*AdderMock", PkgName: thisPkg.Name()}
+	iface.Methods, iface.TypeParams, iface.TypeArgs = parseMethods(thisPkg, "", "Adder")
+
+	mockSrc := genCode(&iface, mockSimple)
+
+	dir, err := ioutil.TempDir("", "ifacegen-mocktest")
+	if err != nil {
+		t.Fatalf("create tempdir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module ifacegenmocktest\n\ngo 1.21\n"), 0600); err != nil {
+		t.Fatalf("write go.mod: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "mock.go"), mockSrc, 0600); err != nil {
+		t.Fatalf("write mock.go: %v", err)
+	}
+
+	const driver = `package main
+
+import "fmt"
+
+func main() {
+	m := &AdderMock{}
+	m.AddMock = func(x, y int) int { return x + y }
+
+	if got := m.Add(2, 3); got != 5 {
+		panic(fmt.Sprintf("Add(2, 3) = %d, want 5", got))
+	}
+	if n := m.AddCallCount(); n != 1 {
+		panic(fmt.Sprintf("AddCallCount() = %d, want 1", n))
+	}
+	calls := m.AddCalls()
+	if len(calls) != 1 || calls[0].X != 2 || calls[0].Y != 3 {
+		panic(fmt.Sprintf("AddCalls() = %+v, want one call with X=2, Y=3", calls))
+	}
+
+	m.Reset()
+	if n := m.AddCallCount(); n != 0 {
+		panic(fmt.Sprintf("after Reset, AddCallCount() = %d, want 0", n))
+	}
+	if calls := m.AddCalls(); len(calls) != 0 {
+		panic(fmt.Sprintf("after Reset, AddCalls() = %+v, want none", calls))
+	}
+
+	fmt.Println("OK")
+}
+`
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), []byte(driver), 0600); err != nil {
+		t.Fatalf("write main.go: %v", err)
+	}
+
+	cmd := exec.Command("go", "run", ".")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("run generated mock: %v\n%s", err, out)
+	}
+	if got := strings.TrimSpace(string(out)); got != "OK" {
+		t.Fatalf("unexpected output: %q", got)
+	}
+}
+
+// TestReflectProgCodeIsValidGo parses reflectProgTpl's output as Go
+// source. Like controllerTpl, it quotes its import path with
+// {{printf "%q" ...}}, so the same html/template-escaping regression
+// would have broken -reflect mode silently.
+func TestReflectProgCodeIsValidGo(t *testing.T) {
+	var buf bytes.Buffer
+	data := struct {
+		ImportPath string
+		Interface  string
+	}{"net/http", "Handler"}
+	if err := reflectProgTpl.Execute(&buf, data); err != nil {
+		t.Fatalf("execute reflect program template: %v", err)
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), "reflect_prog.go", buf.Bytes(), 0); err != nil {
+		t.Fatalf("generated reflect program is not valid Go: %v\n%s", err, buf.Bytes())
+	}
+}
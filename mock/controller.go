@@ -0,0 +1,288 @@
+// Package mock is the runtime support for ifacegen's "controller" mock
+// mode (-m=controller). Generated mocks call into a Controller to record
+// expectations and dispatch calls, gomock-style:
+//
+//   ctrl := mock.NewController(t)
+//   defer ctrl.Finish()
+//   m := NewFooMock(ctrl)
+//   m.EXPECT().Foo(mock.Eq("a"), mock.Any()).Return(nil).Times(2)
+package mock
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// TestReporter is the subset of *testing.T that Controller needs. It lets
+// callers pass a *testing.T without this package importing "testing".
+type TestReporter interface {
+	Errorf(format string, args ...interface{})
+	Fatalf(format string, args ...interface{})
+}
+
+// Matcher reports whether an argument received by a mock matches an
+// expectation.
+type Matcher interface {
+	Matches(x interface{}) bool
+	String() string
+}
+
+type matcherFunc struct {
+	matches func(x interface{}) bool
+	desc    string
+}
+
+func (m matcherFunc) Matches(x interface{}) bool { return m.matches(x) }
+func (m matcherFunc) String() string             { return m.desc }
+
+// Eq returns a Matcher that matches x via reflect.DeepEqual.
+func Eq(x interface{}) Matcher {
+	return matcherFunc{
+		matches: func(y interface{}) bool { return reflect.DeepEqual(x, y) },
+		desc:    fmt.Sprintf("is equal to %v", x),
+	}
+}
+
+// Nil returns a Matcher that matches nil, untyped or typed.
+func Nil() Matcher {
+	return matcherFunc{
+		matches: func(x interface{}) bool {
+			if x == nil {
+				return true
+			}
+			v := reflect.ValueOf(x)
+			switch v.Kind() {
+			case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+				return v.IsNil()
+			}
+			return false
+		},
+		desc: "is nil",
+	}
+}
+
+// Any returns a Matcher that matches any value.
+func Any() Matcher {
+	return matcherFunc{
+		matches: func(interface{}) bool { return true },
+		desc:    "is anything",
+	}
+}
+
+// Not returns a Matcher that matches when m does not.
+func Not(m Matcher) Matcher {
+	return matcherFunc{
+		matches: func(x interface{}) bool { return !m.Matches(x) },
+		desc:    fmt.Sprintf("not(%s)", m),
+	}
+}
+
+// AssignableToTypeOf returns a Matcher that matches any value assignable
+// to the type of x.
+func AssignableToTypeOf(x interface{}) Matcher {
+	ty := reflect.TypeOf(x)
+	return matcherFunc{
+		matches: func(y interface{}) bool {
+			if y == nil {
+				return false
+			}
+			return reflect.TypeOf(y).AssignableTo(ty)
+		},
+		desc: fmt.Sprintf("is assignable to %v", ty),
+	}
+}
+
+func toMatcher(x interface{}) Matcher {
+	if m, ok := x.(Matcher); ok {
+		return m
+	}
+	return Eq(x)
+}
+
+// unbounded marks Call.maxCalls as having no upper limit, set by AnyTimes
+// and MinTimes.
+const unbounded = -1
+
+// Call represents an expected call recorded via a mock's recorder.
+type Call struct {
+	receiver interface{}
+	method   string
+	args     []Matcher
+
+	minCalls, maxCalls int
+	numCalls           int
+
+	rets   []interface{}
+	action func(args []interface{}) []interface{}
+}
+
+func newCall(receiver interface{}, method string, args []interface{}) *Call {
+	matchers := make([]Matcher, len(args))
+	for i, a := range args {
+		matchers[i] = toMatcher(a)
+	}
+	return &Call{
+		receiver: receiver,
+		method:   method,
+		args:     matchers,
+		minCalls: 1,
+		maxCalls: 1,
+	}
+}
+
+// Return sets the values returned by the call.
+func (c *Call) Return(rets ...interface{}) *Call {
+	c.rets = rets
+	return c
+}
+
+// Do sets a function to run (for side effects) when the call is matched.
+// f's signature must match the mocked method's parameters; its return
+// values, if any, are ignored.
+func (c *Call) Do(f interface{}) *Call {
+	fv := reflect.ValueOf(f)
+	c.action = func(args []interface{}) []interface{} {
+		callFunc(fv, args)
+		return c.rets
+	}
+	return c
+}
+
+// DoAndReturn sets a function to run when the call is matched; its
+// return values are returned from the mocked method.
+func (c *Call) DoAndReturn(f interface{}) *Call {
+	fv := reflect.ValueOf(f)
+	c.action = func(args []interface{}) []interface{} {
+		return callFunc(fv, args)
+	}
+	return c
+}
+
+func callFunc(fv reflect.Value, args []interface{}) []interface{} {
+	in := make([]reflect.Value, len(args))
+	ft := fv.Type()
+	for i, a := range args {
+		if a == nil {
+			in[i] = reflect.Zero(ft.In(i))
+		} else {
+			in[i] = reflect.ValueOf(a)
+		}
+	}
+	out := fv.Call(in)
+	rets := make([]interface{}, len(out))
+	for i, v := range out {
+		rets[i] = v.Interface()
+	}
+	return rets
+}
+
+// Times sets the exact number of times the call is expected.
+func (c *Call) Times(n int) *Call {
+	c.minCalls, c.maxCalls = n, n
+	return c
+}
+
+// MinTimes sets the minimum number of times the call is expected, with
+// no upper bound unless MaxTimes is also called.
+func (c *Call) MinTimes(n int) *Call {
+	if c.minCalls == 1 && c.maxCalls == 1 {
+		c.maxCalls = unbounded
+	}
+	c.minCalls = n
+	return c
+}
+
+// MaxTimes sets the maximum number of times the call is expected.
+func (c *Call) MaxTimes(n int) *Call {
+	if c.minCalls == 1 && c.maxCalls == 1 {
+		c.minCalls = 0
+	}
+	c.maxCalls = n
+	return c
+}
+
+// AnyTimes allows the call to happen any number of times, including zero.
+func (c *Call) AnyTimes() *Call {
+	c.minCalls, c.maxCalls = 0, unbounded
+	return c
+}
+
+func (c *Call) matches(method string, args []interface{}) bool {
+	if c.method != method || len(c.args) != len(args) {
+		return false
+	}
+	for i, m := range c.args {
+		if !m.Matches(args[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c *Call) exhausted() bool {
+	return c.maxCalls != unbounded && c.numCalls >= c.maxCalls
+}
+
+// Controller tracks expected calls recorded by generated mocks and
+// verifies them on Finish.
+type Controller struct {
+	t TestReporter
+
+	mu    sync.Mutex
+	calls []*Call
+}
+
+// NewController returns a Controller that reports unmet expectations and
+// unexpected calls to t.
+func NewController(t TestReporter) *Controller {
+	return &Controller{t: t}
+}
+
+// RecordCall is called from a mock's recorder to register an expected
+// call, returning the *Call so the test can chain Return/Times/etc.
+func (c *Controller) RecordCall(receiver interface{}, method string, args ...interface{}) *Call {
+	call := newCall(receiver, method, args)
+	c.mu.Lock()
+	c.calls = append(c.calls, call)
+	c.mu.Unlock()
+	return call
+}
+
+// Call is called from a mock's method to find and run the next matching
+// expectation, in FIFO order, and returns its results.
+func (c *Controller) Call(receiver interface{}, method string, args ...interface{}) []interface{} {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, call := range c.calls {
+		if call.receiver != receiver || call.exhausted() || !call.matches(method, args) {
+			continue
+		}
+		call.numCalls++
+		if call.action != nil {
+			return call.action(args)
+		}
+		return call.rets
+	}
+	c.t.Fatalf("unexpected call to %s%v on %v", method, args, receiver)
+	return nil
+}
+
+// Finish verifies that every expected call happened within its
+// min/max bounds. It should be called once all expected calls should
+// have happened, typically via defer.
+func (c *Controller) Finish() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, call := range c.calls {
+		if call.numCalls < call.minCalls {
+			c.t.Errorf("expected call to %s%v on %v: got %d calls, want at least %d",
+				call.method, call.args, call.receiver, call.numCalls, call.minCalls)
+		} else if call.maxCalls != unbounded && call.numCalls > call.maxCalls {
+			c.t.Errorf("expected call to %s%v on %v: got %d calls, want at most %d",
+				call.method, call.args, call.receiver, call.numCalls, call.maxCalls)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package mock
+
+import (
+	"fmt"
+	"testing"
+)
+
+// fakeReporter is a TestReporter that records messages instead of
+// failing the test driving the Controller under test.
+type fakeReporter struct {
+	errors []string
+	fatals []string
+}
+
+func (f *fakeReporter) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func (f *fakeReporter) Fatalf(format string, args ...interface{}) {
+	f.fatals = append(f.fatals, fmt.Sprintf(format, args...))
+}
+
+func TestControllerTimesExact(t *testing.T) {
+	r := &fakeReporter{}
+	c := NewController(r)
+	c.RecordCall("recv", "Foo").Times(2)
+	c.Call("recv", "Foo")
+	c.Call("recv", "Foo")
+	c.Finish()
+	if len(r.errors) != 0 || len(r.fatals) != 0 {
+		t.Fatalf("unexpected reports: errors=%v fatals=%v", r.errors, r.fatals)
+	}
+}
+
+func TestControllerTimesTooFew(t *testing.T) {
+	r := &fakeReporter{}
+	c := NewController(r)
+	c.RecordCall("recv", "Foo").Times(2)
+	c.Call("recv", "Foo")
+	c.Finish()
+	if len(r.errors) != 1 {
+		t.Fatalf("got %d Finish errors, want 1: %v", len(r.errors), r.errors)
+	}
+}
+
+func TestControllerTimesTooMany(t *testing.T) {
+	r := &fakeReporter{}
+	c := NewController(r)
+	c.RecordCall("recv", "Foo").Times(1)
+	c.Call("recv", "Foo")
+	c.Call("recv", "Foo") // unmatched: the one expectation is exhausted
+	if len(r.fatals) != 1 {
+		t.Fatalf("got %d Fatalf calls, want 1: %v", len(r.fatals), r.fatals)
+	}
+}
+
+func TestControllerUnexpectedCall(t *testing.T) {
+	r := &fakeReporter{}
+	c := NewController(r)
+	c.Call("recv", "Foo")
+	if len(r.fatals) != 1 {
+		t.Fatalf("got %d Fatalf calls, want 1: %v", len(r.fatals), r.fatals)
+	}
+}
+
+func TestControllerFIFOOrderAndReturn(t *testing.T) {
+	r := &fakeReporter{}
+	c := NewController(r)
+	c.RecordCall("recv", "Foo").Return(1)
+	c.RecordCall("recv", "Foo").Return(2)
+
+	got1 := c.Call("recv", "Foo")
+	got2 := c.Call("recv", "Foo")
+	if got1[0] != 1 || got2[0] != 2 {
+		t.Errorf("got %v, %v; want FIFO order 1, 2", got1, got2)
+	}
+	c.Finish()
+	if len(r.errors) != 0 {
+		t.Errorf("unexpected Finish errors: %v", r.errors)
+	}
+}
+
+func TestControllerArgsMustMatch(t *testing.T) {
+	r := &fakeReporter{}
+	c := NewController(r)
+	c.RecordCall("recv", "Foo", Eq(1)).Return("one")
+	c.RecordCall("recv", "Foo", Eq(2)).Return("two")
+
+	if got := c.Call("recv", "Foo", 2); got[0] != "two" {
+		t.Errorf("Call(2) = %v, want [two]", got)
+	}
+	if got := c.Call("recv", "Foo", 1); got[0] != "one" {
+		t.Errorf("Call(1) = %v, want [one]", got)
+	}
+}
+
+func TestControllerMinMaxTimes(t *testing.T) {
+	r := &fakeReporter{}
+	c := NewController(r)
+	c.RecordCall("recv", "Foo").MinTimes(1).MaxTimes(3)
+	c.Call("recv", "Foo")
+	c.Call("recv", "Foo")
+	c.Finish()
+	if len(r.errors) != 0 {
+		t.Fatalf("unexpected Finish errors: %v", r.errors)
+	}
+}
+
+func TestControllerMinTimesUnmet(t *testing.T) {
+	r := &fakeReporter{}
+	c := NewController(r)
+	c.RecordCall("recv", "Foo").MinTimes(2)
+	c.Call("recv", "Foo")
+	c.Finish()
+	if len(r.errors) != 1 {
+		t.Fatalf("got %d Finish errors, want 1: %v", len(r.errors), r.errors)
+	}
+}
+
+func TestControllerAnyTimes(t *testing.T) {
+	r := &fakeReporter{}
+	c := NewController(r)
+	c.RecordCall("recv", "Foo").AnyTimes()
+	c.Finish()
+	if len(r.errors) != 0 {
+		t.Fatalf("AnyTimes with zero calls should not fail Finish: %v", r.errors)
+	}
+}
+
+func TestCallDoAndReturn(t *testing.T) {
+	r := &fakeReporter{}
+	c := NewController(r)
+	c.RecordCall("recv", "Add", 2, 3).DoAndReturn(func(a, b int) int { return a + b })
+
+	got := c.Call("recv", "Add", 2, 3)
+	if got[0] != 5 {
+		t.Fatalf("got %v, want [5]", got)
+	}
+	c.Finish()
+}
+
+func TestCallDo(t *testing.T) {
+	r := &fakeReporter{}
+	c := NewController(r)
+	var got int
+	c.RecordCall("recv", "Set", 7).Do(func(v int) { got = v })
+
+	c.Call("recv", "Set", 7)
+	if got != 7 {
+		t.Fatalf("Do side effect did not run: got = %d, want 7", got)
+	}
+	c.Finish()
+}
+
+func TestMatchers(t *testing.T) {
+	tests := []struct {
+		name string
+		m    Matcher
+		x    interface{}
+		want bool
+	}{
+		{"Eq match", Eq(5), 5, true},
+		{"Eq mismatch", Eq(5), 6, false},
+		{"Nil untyped nil", Nil(), nil, true},
+		{"Nil typed nil pointer", Nil(), (*int)(nil), true},
+		{"Nil non-nil", Nil(), 5, false},
+		{"Any", Any(), "whatever", true},
+		{"Not matches when wrapped doesn't", Not(Eq(5)), 6, true},
+		{"Not doesn't match when wrapped does", Not(Eq(5)), 5, false},
+		{"AssignableToTypeOf match", AssignableToTypeOf(0), 5, true},
+		{"AssignableToTypeOf mismatch", AssignableToTypeOf(0), "s", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.m.Matches(tt.x); got != tt.want {
+				t.Errorf("%s.Matches(%v) = %v, want %v", tt.name, tt.x, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,7 @@
+package main
+
+// Adder is a tiny interface used to exercise the default (-m) mock's
+// call recording and Reset behavior (see TestDefaultMockRecordsCallsAndReset).
+type Adder interface {
+	Add(x, y int) int
+}